@@ -0,0 +1,80 @@
+// Package backup implements the core, UI-independent logic for locating an
+// iTunes backup and recovering its Restrictions PIN or Screen Time
+// passcode, so it can be driven from pinfinder's CLI or embedded in other
+// tools (e.g. a forensics pipeline).
+package backup
+
+import (
+	"errors"
+	"os"
+	"os/user"
+	"path/filepath"
+	"runtime"
+	"time"
+)
+
+// ErrNoBackupsFound is returned by FindLatestBackup when backupDir
+// contains no backup subdirectories.
+var ErrNoBackupsFound = errors.New("backup: no backup directories found")
+
+func isDir(p string) bool {
+	s, err := os.Stat(p)
+	if err != nil {
+		return false
+	}
+	return s.IsDir()
+}
+
+// FindSyncDir returns the directory iTunes stores backups in on the
+// current OS.
+func FindSyncDir() (string, error) {
+	usr, err := user.Current()
+	if err != nil {
+		return "", err
+	}
+	var dir string
+	switch runtime.GOOS {
+	case "darwin":
+		dir = filepath.Join(usr.HomeDir, "Library", "Application Support", "MobileSync", "Backup")
+	case "windows":
+		// vista & newer
+		dir = filepath.Join(usr.HomeDir, "AppData", "Roaming", "Apple Computer", "MobileSync", "Backup")
+		if !isDir(dir) {
+			// XP; untested.
+			dir = filepath.Join("Documents and Settings", usr.Username, "Application Data", "Apple Computer", "MobileSync", "Backup")
+		}
+	default:
+		return "", errors.New("backup: could not detect backup directory for this operating system; pass explicitly")
+	}
+	if !isDir(dir) {
+		return "", errors.New("backup: directory " + dir + " does not exist")
+	}
+	return dir, nil
+}
+
+// FindLatestBackup returns the most recently modified backup directory
+// directly inside backupDir.
+func FindLatestBackup(backupDir string) (string, error) {
+	d, err := os.Open(backupDir)
+	if err != nil {
+		return "", err
+	}
+	defer d.Close()
+	files, err := d.Readdir(10000)
+	if err != nil {
+		return "", err
+	}
+	var newest string
+	var lastMT time.Time
+
+	for _, fi := range files {
+		if mt := fi.ModTime(); mt.After(lastMT) {
+			lastMT = mt
+			newest = fi.Name()
+		}
+	}
+	if newest == "" {
+		return "", ErrNoBackupsFound
+	}
+	return filepath.Join(backupDir, newest), nil
+}
@@ -0,0 +1,162 @@
+package backup
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+)
+
+// CandidateSource streams PIN/passcode guesses for FindPIN to test,
+// decoupling how candidates are generated (a numeric range, a wordlist,
+// ...) from how they're dispatched to worker goroutines.
+type CandidateSource interface {
+	// Candidates sends guesses on the returned channel until exhausted or
+	// ctx is cancelled, then closes it.
+	Candidates(ctx context.Context) <-chan string
+	// Count returns the number of candidates this source will produce, or
+	// -1 if that can't be known up front (e.g. a wordlist).
+	Count() int64
+}
+
+// numericSource produces every zero-padded decimal number with the given
+// number of digits, e.g. "0000".."9999" for digits=4.
+type numericSource struct {
+	digits int
+}
+
+func (s numericSource) Count() int64 {
+	n := int64(1)
+	for i := 0; i < s.digits; i++ {
+		n *= 10
+	}
+	return n
+}
+
+func (s numericSource) Candidates(ctx context.Context) <-chan string {
+	out := make(chan string)
+	go func() {
+		defer close(out)
+		format := fmt.Sprintf("%%0%dd", s.digits)
+		for i := int64(0); i < s.Count(); i++ {
+			select {
+			case out <- fmt.Sprintf(format, i):
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+	return out
+}
+
+// alphanumericSource produces every string of length minLen..maxLen drawn
+// from charset.
+type alphanumericSource struct {
+	charset        string
+	minLen, maxLen int
+}
+
+func (s alphanumericSource) Count() int64 {
+	var total int64
+	base := int64(len(s.charset))
+	for l := s.minLen; l <= s.maxLen; l++ {
+		n := int64(1)
+		for i := 0; i < l; i++ {
+			n *= base
+		}
+		total += n
+	}
+	return total
+}
+
+func (s alphanumericSource) Candidates(ctx context.Context) <-chan string {
+	out := make(chan string)
+	go func() {
+		defer close(out)
+		for l := s.minLen; l <= s.maxLen; l++ {
+			if !s.emit(ctx, out, make([]byte, l), 0) {
+				return
+			}
+		}
+	}()
+	return out
+}
+
+// emit recursively generates every combination of s.charset for the
+// length of buf, filling in from pos onwards. It returns false if ctx was
+// cancelled partway through, so the caller can stop generating longer
+// lengths too.
+func (s alphanumericSource) emit(ctx context.Context, out chan<- string, buf []byte, pos int) bool {
+	if pos == len(buf) {
+		select {
+		case out <- string(buf):
+			return true
+		case <-ctx.Done():
+			return false
+		}
+	}
+	for i := 0; i < len(s.charset); i++ {
+		buf[pos] = s.charset[i]
+		if !s.emit(ctx, out, buf, pos+1) {
+			return false
+		}
+	}
+	return true
+}
+
+// wordlistSource streams candidates from a dictionary file, one per line.
+type wordlistSource struct {
+	path string
+}
+
+func (s wordlistSource) Count() int64 { return -1 }
+
+func (s wordlistSource) Candidates(ctx context.Context) <-chan string {
+	out := make(chan string)
+	go func() {
+		defer close(out)
+		f, err := os.Open(s.path)
+		if err != nil {
+			return
+		}
+		defer f.Close()
+		scanner := bufio.NewScanner(f)
+		for scanner.Scan() {
+			word := strings.TrimSpace(scanner.Text())
+			if word == "" {
+				continue
+			}
+			select {
+			case out <- word:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+	return out
+}
+
+// ParseMode turns a "-mode" flag value into a CandidateSource. charset,
+// minLen and maxLen only apply to mode "alphanumeric".
+func ParseMode(mode, charset string, minLen, maxLen int) (CandidateSource, error) {
+	switch {
+	case mode == "numeric4":
+		return numericSource{digits: 4}, nil
+	case mode == "numeric6":
+		return numericSource{digits: 6}, nil
+	case mode == "alphanumeric":
+		if charset == "" {
+			return nil, errors.New("-mode=alphanumeric requires -charset")
+		}
+		if minLen <= 0 || maxLen < minLen {
+			return nil, errors.New("-mode=alphanumeric requires 0 < -minlen <= -maxlen")
+		}
+		return alphanumericSource{charset: charset, minLen: minLen, maxLen: maxLen}, nil
+	case strings.HasPrefix(mode, "wordlist="):
+		return wordlistSource{path: strings.TrimPrefix(mode, "wordlist=")}, nil
+	default:
+		return nil, fmt.Errorf("unrecognized -mode %q", mode)
+	}
+}
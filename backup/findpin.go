@@ -0,0 +1,105 @@
+package backup
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"runtime"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// ErrPINNotFound is returned by FindPIN when opts.Source is exhausted
+// without finding a match.
+var ErrPINNotFound = errors.New("backup: failed to calculate PIN number")
+
+// Stats reports progress on an in-flight or completed FindPIN call.
+type Stats struct {
+	Guesses int64
+	Elapsed time.Duration
+	// Total is opts.Source.Count(), or -1 if the source can't say up
+	// front how many candidates it will produce (e.g. a wordlist).
+	Total int64
+}
+
+// FindPINOptions configures a FindPIN call.
+type FindPINOptions struct {
+	Cracker Cracker
+	Source  CandidateSource
+	// Progress, if non-nil, is called roughly once a second with the
+	// current guess count and elapsed time.
+	Progress func(Stats)
+}
+
+// FindPIN brute-forces the PIN/passcode protecting key using opts.Cracker,
+// with every worker goroutine pulling guesses off opts.Source's shared
+// channel (so, unlike a fixed range split per core, work stays balanced
+// even when a match is found early or candidates arrive unevenly, as with
+// a wordlist). ctx cancellation stops all workers and returns ctx.Err().
+func FindPIN(ctx context.Context, key, salt []byte, opts FindPINOptions) (string, Stats, error) {
+	if opts.Cracker == nil || opts.Source == nil {
+		return "", Stats{}, errors.New("backup: FindPINOptions.Cracker and Source are required")
+	}
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	candidates := opts.Source.Candidates(ctx)
+	found := make(chan string, 1)
+	var tried int64
+	var wg sync.WaitGroup
+
+	for i := 0; i < runtime.NumCPU(); i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for guess := range candidates {
+				atomic.AddInt64(&tried, 1)
+				if bytes.Equal(opts.Cracker.Derive(guess, salt, len(key)), key) {
+					found <- guess
+					cancel()
+					return
+				}
+			}
+		}()
+	}
+
+	done := make(chan struct{})
+	go func() {
+		wg.Wait()
+		close(done)
+	}()
+
+	total := opts.Source.Count()
+	start := time.Now()
+	stats := func() Stats {
+		return Stats{Guesses: atomic.LoadInt64(&tried), Elapsed: time.Since(start), Total: total}
+	}
+
+	var tick <-chan time.Time
+	if opts.Progress != nil {
+		ticker := time.NewTicker(time.Second)
+		defer ticker.Stop()
+		tick = ticker.C
+	}
+
+	for {
+		select {
+		case pin := <-found:
+			return pin, stats(), nil
+		case <-done:
+			select {
+			case pin := <-found:
+				return pin, stats(), nil
+			default:
+				if err := ctx.Err(); err != nil {
+					return "", stats(), err
+				}
+				return "", stats(), ErrPINNotFound
+			}
+		case <-tick:
+			opts.Progress(stats())
+		}
+	}
+}
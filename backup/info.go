@@ -0,0 +1,106 @@
+package backup
+
+import (
+	"encoding/xml"
+	"io"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// DeviceInfo is the handful of Info.plist fields useful for identifying
+// which device a backup came from and when it was last backed up.
+type DeviceInfo struct {
+	DeviceName     string    `json:"device_name"`
+	ProductType    string    `json:"product_type"`
+	IOSVersion     string    `json:"ios_version"`
+	LastBackupDate time.Time `json:"last_backup_date,omitempty"`
+}
+
+// LoadDeviceInfo reads and parses the Info.plist in backupDir.
+func LoadDeviceInfo(backupDir string) (*DeviceInfo, error) {
+	f, err := os.Open(filepath.Join(backupDir, "Info.plist"))
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	return parseInfoPlist(f)
+}
+
+// parseInfoPlist walks Info.plist's outermost dict directly, pairing each
+// <key> with the value that immediately follows it. Info.plist mixes
+// <string>, <date> and other value types in the same dict, so (unlike the
+// restrictions plist) they can't be collected into type-specific parallel
+// arrays and matched up by index.
+func parseInfoPlist(r io.Reader) (*DeviceInfo, error) {
+	info := &DeviceInfo{}
+	dec := xml.NewDecoder(r)
+
+	var depth int
+	var pendingKey string
+	for {
+		tok, err := dec.Token()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+		if end, ok := tok.(xml.EndElement); ok {
+			if end.Name.Local == "dict" {
+				depth--
+			}
+			continue
+		}
+		start, ok := tok.(xml.StartElement)
+		if !ok {
+			continue
+		}
+		switch start.Name.Local {
+		case "dict":
+			depth++
+		case "key":
+			if depth != 1 {
+				continue
+			}
+			var key string
+			if err := dec.DecodeElement(&key, &start); err != nil {
+				return nil, err
+			}
+			pendingKey = key
+		case "string":
+			if depth != 1 || pendingKey == "" {
+				continue
+			}
+			var value string
+			if err := dec.DecodeElement(&value, &start); err != nil {
+				return nil, err
+			}
+			switch pendingKey {
+			case "Device Name":
+				info.DeviceName = value
+			case "Product Type":
+				info.ProductType = value
+			case "Product Version":
+				info.IOSVersion = value
+			}
+			pendingKey = ""
+		case "date":
+			key := pendingKey
+			pendingKey = ""
+			if depth != 1 || key != "Last Backup Date" {
+				continue
+			}
+			var value string
+			if err := dec.DecodeElement(&value, &start); err != nil {
+				return nil, err
+			}
+			if t, err := time.Parse(time.RFC3339, value); err == nil {
+				info.LastBackupDate = t
+			}
+		default:
+			pendingKey = ""
+		}
+	}
+	return info, nil
+}
@@ -0,0 +1,116 @@
+package backup
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// EnumerateBackups lists every backup subdirectory directly inside
+// syncDir (the MobileSync "Backup" folder), for -all style multi-backup
+// scans.
+func EnumerateBackups(syncDir string) ([]string, error) {
+	d, err := os.Open(syncDir)
+	if err != nil {
+		return nil, err
+	}
+	defer d.Close()
+	fl, err := d.Readdir(-1)
+	if err != nil {
+		return nil, err
+	}
+	var dirs []string
+	for _, fi := range fl {
+		if fi.IsDir() {
+			dirs = append(dirs, filepath.Join(syncDir, fi.Name()))
+		}
+	}
+	if len(dirs) == 0 {
+		return nil, ErrNoBackupsFound
+	}
+	return dirs, nil
+}
+
+// BackupSummary is one row in a multi-backup scan: a single device's
+// backup directory, its metadata, and its restrictions-PIN outcome.
+type BackupSummary struct {
+	Path       string      `json:"backup_path"`
+	Info       *DeviceInfo `json:"device_info,omitempty"`
+	Encrypted  bool        `json:"encrypted"`
+	BackupDate time.Time   `json:"backup_date,omitempty"`
+	PIN        string      `json:"pin,omitempty"`
+	// Status is "found", "not set", "not found", "encrypted (no password
+	// given)", or an error's message for a genuine scan failure.
+	Status string `json:"status"`
+}
+
+// ScanOptions configures ScanBackup.
+type ScanOptions struct {
+	// Password unlocks an encrypted backup; ignored for unencrypted ones.
+	Password string
+	// Cracker overrides the one FindRestrictions/FindEncryptedRestrictions
+	// matched; leave nil to use whichever they picked.
+	Cracker Cracker
+	// Source selects the PIN/passcode keyspace to try; defaults to
+	// numeric4 (the classic 4-digit PIN) if nil.
+	Source CandidateSource
+}
+
+// ScanBackup runs restriction discovery and PIN cracking against a single
+// backup directory, reporting the outcome as a BackupSummary rather than
+// an error, so a multi-backup scan can keep going past one device's
+// failure.
+func ScanBackup(ctx context.Context, dir string, opts ScanOptions) BackupSummary {
+	summary := BackupSummary{Path: dir}
+	if info, err := LoadDeviceInfo(dir); err == nil {
+		summary.Info = info
+		summary.BackupDate = info.LastBackupDate
+	}
+
+	encrypted, _ := IsEncrypted(dir)
+	summary.Encrypted = encrypted
+
+	var pl *Plist
+	var cracker Cracker
+	var err error
+	if encrypted {
+		if opts.Password == "" {
+			summary.Status = "encrypted (no password given)"
+			return summary
+		}
+		pl, cracker, err = FindEncryptedRestrictions(dir, opts.Password)
+	} else {
+		pl, cracker, err = FindRestrictions(dir)
+	}
+	if err != nil {
+		if errors.Is(err, ErrRestrictionsNotConfigured) {
+			summary.Status = "not set"
+		} else {
+			summary.Status = err.Error()
+		}
+		return summary
+	}
+	if opts.Cracker != nil {
+		cracker = opts.Cracker
+	}
+	source := opts.Source
+	if source == nil {
+		source = numericSource{digits: 4}
+	}
+
+	key, salt := ParseRestrictions(pl)
+	pin, _, err := FindPIN(ctx, key, salt, FindPINOptions{Cracker: cracker, Source: source})
+	if err != nil {
+		if errors.Is(err, ErrPINNotFound) {
+			summary.Status = "not found"
+		} else {
+			summary.Status = err.Error()
+		}
+		return summary
+	}
+	summary.PIN = pin
+	summary.Status = "found"
+	return summary
+}
@@ -0,0 +1,111 @@
+package backup
+
+import (
+	"encoding/xml"
+	"fmt"
+	"io"
+	"os"
+)
+
+// Plist is a minimal XML plist representation: the top-level dict's keys,
+// in document order, plus any <data>-valued entries in that same dict,
+// keyed by the <key> that precedes them. Keying by name (rather than
+// Data's positional index, as an earlier version of this type did) keeps
+// lookups correct even for a plist that mixes <data> values with other
+// types, or nests a sub-<dict> — all that matters to pinfinder is the
+// named entries in the outermost dict.
+type Plist struct {
+	Path string
+	Keys []string
+	Data map[string]string
+
+	// passcodeKey and saltKey are stamped on by whichever plistMatcher
+	// recognized this plist, so ParseRestrictions knows which of Data's
+	// entries hold the hash and salt.
+	passcodeKey, saltKey string
+}
+
+// DumpTo writes the plist's original file contents to w, for inclusion in
+// error output when a PIN can't be found.
+func (p *Plist) DumpTo(w io.Writer) error {
+	f, err := os.Open(p.Path)
+	if err != nil {
+		return fmt.Errorf("backup: failed to dump plist data: %w", err)
+	}
+	defer f.Close()
+	io.Copy(w, f)
+	return nil
+}
+
+// LoadPlist reads and decodes the plist file at fn.
+func LoadPlist(fn string) (*Plist, error) {
+	f, err := os.Open(fn)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	p, err := ParsePlist(f)
+	if err != nil {
+		return nil, err
+	}
+	p.Path = fn
+	return p, nil
+}
+
+// ParsePlist decodes an XML plist from r without tying the result to a
+// file on disk, for plists that have been decrypted into memory rather
+// than read directly off disk. Only the outermost dict's entries are
+// recorded: a <key> followed by a <data> is captured in Data under that
+// key name; a <key> followed by anything else (a nested <dict>, <true/>,
+// <string>, ...) is recorded in Keys but otherwise skipped.
+func ParsePlist(r io.Reader) (*Plist, error) {
+	p := &Plist{Data: make(map[string]string)}
+	dec := xml.NewDecoder(r)
+
+	var depth int
+	var pendingKey string
+	for {
+		tok, err := dec.Token()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+		if end, ok := tok.(xml.EndElement); ok {
+			if end.Name.Local == "dict" {
+				depth--
+			}
+			continue
+		}
+		start, ok := tok.(xml.StartElement)
+		if !ok {
+			continue
+		}
+		switch start.Name.Local {
+		case "dict":
+			depth++
+		case "key":
+			if depth != 1 {
+				continue
+			}
+			var key string
+			if err := dec.DecodeElement(&key, &start); err != nil {
+				return nil, err
+			}
+			p.Keys = append(p.Keys, key)
+			pendingKey = key
+		case "data":
+			if depth != 1 || pendingKey == "" {
+				continue
+			}
+			var data string
+			if err := dec.DecodeElement(&data, &start); err != nil {
+				return nil, err
+			}
+			p.Data[pendingKey] = data
+			pendingKey = ""
+		}
+	}
+	return p, nil
+}
@@ -0,0 +1,178 @@
+package backup
+
+import (
+	"bytes"
+	"crypto/sha1"
+	"encoding/base64"
+	"errors"
+	"hash"
+	"os"
+	"path"
+	"strings"
+
+	"github.com/fcgll520/pinfinder/backupcrypto"
+	"golang.org/x/crypto/pbkdf2"
+)
+
+// ErrNoPlistFiles is returned by FindRestrictions when fpath contains no
+// plist files at all, suggesting it isn't a backup directory.
+var ErrNoPlistFiles = errors.New("backup: no plist files found; are you sure you have the right directory?")
+
+// ErrRestrictionsNotConfigured is returned by FindRestrictions and
+// FindEncryptedRestrictions when a backup was searched successfully but
+// none of its plists matched a known restrictions format, meaning the
+// device most likely doesn't have Restrictions turned on (as opposed to a
+// real scan failure, e.g. a corrupt backup).
+//
+// This only covers the legacy iOS 7-11 Restrictions PIN. The iOS 12+
+// Screen Time passcode is stored in the keychain (KeychainDomain's
+// keychain-backup.plist), not as a plist under HomeDomain, and recovering
+// it would mean parsing that file's per-item wrapping — unimplemented
+// here, since its exact layout hasn't been confirmed against a real iOS
+// 12+ backup. Don't add a Screen Time "Cracker" back in without that
+// verification; a previous version of this file guessed at plist key
+// names for it that don't correspond to anything Screen Time actually
+// writes.
+var ErrRestrictionsNotConfigured = errors.New("backup: no matching restrictions plist found; are parental restrictions turned on?")
+
+// Cracker derives a key from a PIN guess the same way iOS hashes the
+// legacy Restrictions PIN, so FindPIN can brute-force it without needing
+// to know the hash parameters itself.
+type Cracker interface {
+	// Derive hashes guess with salt, returning a key of the same length
+	// as the target key so it can be compared with bytes.Equal.
+	Derive(guess string, salt []byte, keyLen int) []byte
+}
+
+// pbkdf2Cracker implements Cracker for a single PBKDF2 hash function and
+// iteration count.
+type pbkdf2Cracker struct {
+	hash       func() hash.Hash
+	iterations int
+}
+
+func (c pbkdf2Cracker) Derive(guess string, salt []byte, keyLen int) []byte {
+	return pbkdf2.Key([]byte(guess), salt, c.iterations, keyLen, c.hash)
+}
+
+// RestrictionsCracker matches the legacy iOS 7-11 Restrictions PIN.
+var RestrictionsCracker Cracker = pbkdf2Cracker{hash: sha1.New, iterations: 1000}
+
+// plistMatcher recognizes one generation of restrictions-PIN storage by
+// the presence of its passcode/salt dict keys, and carries the Cracker
+// needed to brute-force it.
+type plistMatcher struct {
+	cracker     Cracker
+	passcodeKey string
+	saltKey     string
+}
+
+// match reports whether pl has this matcher's passcode and salt entries,
+// and if so stamps their key names onto pl for ParseRestrictions to use.
+func (m plistMatcher) match(pl *Plist) bool {
+	if _, ok := pl.Data[m.passcodeKey]; !ok {
+		return false
+	}
+	if _, ok := pl.Data[m.saltKey]; !ok {
+		return false
+	}
+	pl.passcodeKey, pl.saltKey = m.passcodeKey, m.saltKey
+	return true
+}
+
+// restrictionsPasswordKey and restrictionsSaltKey are the dict keys under
+// which the legacy Restrictions plist stores its PBKDF2 hash and salt.
+const (
+	restrictionsPasswordKey = "RestrictionsPasswordKey"
+	restrictionsSaltKey     = "RestrictionsPasswordSalt"
+)
+
+var plistMatchers = []plistMatcher{
+	{cracker: RestrictionsCracker, passcodeKey: restrictionsPasswordKey, saltKey: restrictionsSaltKey},
+}
+
+// Domain and relative path of the legacy Restrictions plist inside an
+// iTunes backup, as recorded by Manifest.db when the backup is encrypted.
+const (
+	restrictionsDomain  = "HomeDomain"
+	restrictionsRelPath = "Library/Preferences/com.apple.restrictionspassword.plist"
+)
+
+// IsEncrypted reports whether the backup at fpath is password protected.
+func IsEncrypted(fpath string) (bool, error) {
+	return backupcrypto.ManifestIsEncrypted(fpath)
+}
+
+// FindRestrictions scans fpath for the plist file holding the legacy
+// Restrictions PIN hash. Every backup file is renamed to its content hash
+// on disk, so this has to check each candidate plist's structure rather
+// than a filename.
+func FindRestrictions(fpath string) (*Plist, Cracker, error) {
+	d, err := os.Open(fpath)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer d.Close()
+	fl, err := d.Readdir(-1)
+	if err != nil {
+		return nil, nil, err
+	}
+	c := 0
+	for _, fi := range fl {
+		if !fi.Mode().IsRegular() {
+			continue
+		}
+		// The Restrictions plist is just the hash and salt, so it's tiny.
+		if size := fi.Size(); size < 300 || size > 700 {
+			continue
+		}
+		pl, err := LoadPlist(path.Join(fpath, fi.Name()))
+		if err != nil {
+			continue
+		}
+		c++
+		for _, m := range plistMatchers {
+			if m.match(pl) {
+				return pl, m.cracker, nil
+			}
+		}
+	}
+	if c == 0 {
+		return nil, nil, ErrNoPlistFiles
+	}
+	return nil, nil, ErrRestrictionsNotConfigured
+}
+
+// FindEncryptedRestrictions decrypts and parses the legacy Restrictions
+// plist out of a password-protected backup.
+func FindEncryptedRestrictions(fpath, password string) (*Plist, Cracker, error) {
+	u, err := backupcrypto.NewUnlocker(fpath, password)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer u.Close()
+
+	data, err := u.DecryptManifestEntry(restrictionsDomain, restrictionsRelPath)
+	if err != nil {
+		return nil, nil, ErrRestrictionsNotConfigured
+	}
+	pl, err := ParsePlist(bytes.NewReader(data))
+	if err != nil {
+		return nil, nil, ErrRestrictionsNotConfigured
+	}
+	for _, m := range plistMatchers {
+		if m.match(pl) {
+			pl.Path = restrictionsRelPath + " (decrypted)"
+			return pl, m.cracker, nil
+		}
+	}
+	return nil, nil, ErrRestrictionsNotConfigured
+}
+
+// ParseRestrictions extracts the PIN hash and salt out of a restrictions
+// plist matched by FindRestrictions or FindEncryptedRestrictions.
+func ParseRestrictions(pl *Plist) (pw, salt []byte) {
+	pw, _ = base64.StdEncoding.DecodeString(strings.TrimSpace(pl.Data[pl.passcodeKey]))
+	salt, _ = base64.StdEncoding.DecodeString(strings.TrimSpace(pl.Data[pl.saltKey]))
+	return pw, salt
+}
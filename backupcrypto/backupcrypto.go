@@ -0,0 +1,119 @@
+package backupcrypto
+
+import (
+	"encoding/binary"
+	"fmt"
+	"os"
+)
+
+// Unlocker derives and caches the protection-class keys for a single
+// encrypted backup, decrypts its Manifest.db once up front, and can then
+// decrypt individual files out of the backup without re-deriving the
+// password hash each time.
+type Unlocker struct {
+	backupDir      string
+	keybag         *Keybag
+	manifestDBPath string // path to a temp file holding the decrypted Manifest.db
+}
+
+// NewUnlocker parses backupDir's Manifest.plist, confirms the backup is
+// encrypted, derives its protection-class keys from password, and
+// decrypts Manifest.db (which is itself AES-CBC encrypted, under a key
+// wrapped by one of those protection classes) into a temp file.
+func NewUnlocker(backupDir, password string) (*Unlocker, error) {
+	encrypted, err := ManifestIsEncrypted(backupDir)
+	if err != nil {
+		return nil, err
+	}
+	if !encrypted {
+		return nil, fmt.Errorf("backupcrypto: backup at %s is not encrypted", backupDir)
+	}
+	kb, err := ManifestKeybag(backupDir)
+	if err != nil {
+		return nil, err
+	}
+	kek := DeriveKeybagKey(password, kb)
+	for _, pc := range kb.Classes {
+		if pc.WrappedKey == nil {
+			continue
+		}
+		if err := pc.UnwrapClass(kek); err != nil {
+			return nil, fmt.Errorf("backupcrypto: incorrect backup password: %w", err)
+		}
+	}
+
+	manifestDBPath, err := decryptManifestDB(backupDir, kb)
+	if err != nil {
+		return nil, fmt.Errorf("backupcrypto: decrypting Manifest.db: %w", err)
+	}
+	return &Unlocker{backupDir: backupDir, keybag: kb, manifestDBPath: manifestDBPath}, nil
+}
+
+// decryptManifestDB unwraps the ManifestKey recorded in Manifest.plist
+// with the keybag and uses it to decrypt Manifest.db into a temp file,
+// returning that file's path.
+func decryptManifestDB(backupDir string, kb *Keybag) (string, error) {
+	raw, err := ManifestKey(backupDir)
+	if err != nil {
+		return "", err
+	}
+	if len(raw) < 4 {
+		return "", fmt.Errorf("backupcrypto: ManifestKey blob is too short (%d bytes)", len(raw))
+	}
+	class := int(binary.LittleEndian.Uint32(raw[:4]))
+	pc, ok := kb.Classes[class]
+	if !ok || pc.Key == nil {
+		return "", fmt.Errorf("backupcrypto: no unwrapped key for protection class %d", class)
+	}
+	manifestKey, err := UnwrapKey(pc.Key, raw[4:])
+	if err != nil {
+		return "", fmt.Errorf("backupcrypto: unwrapping Manifest.db key: %w", err)
+	}
+
+	plaintext, err := DecryptFile(backupDir+"/Manifest.db", manifestKey)
+	if err != nil {
+		return "", err
+	}
+
+	tmp, err := os.CreateTemp("", "pinfinder-manifest-*.db")
+	if err != nil {
+		return "", err
+	}
+	defer tmp.Close()
+	if _, err := tmp.Write(plaintext); err != nil {
+		os.Remove(tmp.Name())
+		return "", err
+	}
+	return tmp.Name(), nil
+}
+
+// Close removes the temp file holding the decrypted Manifest.db. Callers
+// must call this once they're done with the Unlocker: that file is a
+// decrypted copy of sensitive backup metadata and must not be left behind
+// on disk.
+func (u *Unlocker) Close() error {
+	return os.Remove(u.manifestDBPath)
+}
+
+// DecryptManifestEntry decrypts the file stored at domain/relativePath
+// within the backup (as recorded in Manifest.db) and returns its
+// plaintext, e.g. a restrictions plist.
+func (u *Unlocker) DecryptManifestEntry(domain, relativePath string) ([]byte, error) {
+	fk, err := LookupFileKey(u.manifestDBPath, domain, relativePath)
+	if err != nil {
+		return nil, err
+	}
+	pc, ok := u.keybag.Classes[fk.Class]
+	if !ok || pc.Key == nil {
+		return nil, fmt.Errorf("backupcrypto: no unwrapped key for protection class %d", fk.Class)
+	}
+	fileKey, err := UnwrapKey(pc.Key, fk.WrappedKey)
+	if err != nil {
+		return nil, fmt.Errorf("backupcrypto: unwrapping per-file key: %w", err)
+	}
+	// Backup file contents are stored under their fileID (the SHA1 of
+	// "domain-relativePath"), sharded into a subdirectory named by the
+	// first two hex characters of the fileID.
+	id := fileID(domain, relativePath)
+	return DecryptFile(u.backupDir+"/"+id[:2]+"/"+id, fileKey)
+}
@@ -0,0 +1,56 @@
+package backupcrypto
+
+import (
+	"bytes"
+	"crypto/aes"
+	"crypto/cipher"
+	"errors"
+	"fmt"
+	"os"
+)
+
+// DecryptFile reads the (content-addressed) backup file at path and
+// decrypts it with key; see decryptBytes.
+func DecryptFile(path string, key []byte) ([]byte, error) {
+	ciphertext, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	plaintext, err := decryptBytes(ciphertext, key)
+	if err != nil {
+		return nil, fmt.Errorf("backupcrypto: decrypting %s: %w", path, err)
+	}
+	return plaintext, nil
+}
+
+// decryptBytes decrypts ciphertext with key using AES-CBC with a zero IV,
+// as iOS backups do, and strips the trailing PKCS#7 padding. This is used
+// both for individual backup files and for Manifest.db itself.
+func decryptBytes(ciphertext, key []byte) ([]byte, error) {
+	if len(ciphertext)%aes.BlockSize != 0 {
+		return nil, errors.New("backupcrypto: ciphertext is not a multiple of the AES block size")
+	}
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	iv := make([]byte, aes.BlockSize)
+	plaintext := make([]byte, len(ciphertext))
+	cipher.NewCBCDecrypter(block, iv).CryptBlocks(plaintext, ciphertext)
+
+	return stripPKCS7(plaintext)
+}
+
+func stripPKCS7(data []byte) ([]byte, error) {
+	if len(data) == 0 {
+		return nil, errors.New("backupcrypto: cannot unpad empty plaintext")
+	}
+	n := int(data[len(data)-1])
+	if n == 0 || n > aes.BlockSize || n > len(data) {
+		return nil, errors.New("backupcrypto: invalid PKCS#7 padding")
+	}
+	if !bytes.Equal(data[len(data)-n:], bytes.Repeat([]byte{byte(n)}, n)) {
+		return nil, errors.New("backupcrypto: invalid PKCS#7 padding")
+	}
+	return data[:len(data)-n], nil
+}
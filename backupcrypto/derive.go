@@ -0,0 +1,38 @@
+package backupcrypto
+
+import (
+	"crypto/sha1"
+	"crypto/sha256"
+
+	"golang.org/x/crypto/pbkdf2"
+)
+
+// DeriveKeybagKey derives the key-encryption-key (KEK) used to unwrap a
+// Keybag's protection-class keys from the backup password, following the
+// two-stage scheme introduced in iOS 10.2: an inner PBKDF2-SHA256 pass over
+// the password (using the keybag's DPSL/DPIC), followed by an outer
+// PBKDF2-SHA1 pass over the result (using the keybag's SALT/ITER).
+//
+// Backups from devices older than iOS 10.2 don't populate DPSL/DPIC; in
+// that case the inner pass is skipped and the password is fed to the outer
+// pass directly.
+func DeriveKeybagKey(password string, kb *Keybag) []byte {
+	const keyLen = 32
+
+	passKey := []byte(password)
+	if len(kb.DPSL) > 0 && kb.DPIC > 0 {
+		passKey = pbkdf2.Key([]byte(password), kb.DPSL, kb.DPIC, keyLen, sha256.New)
+	}
+	return pbkdf2.Key(passKey, kb.Salt, kb.Iterations, keyLen, sha1.New)
+}
+
+// UnwrapClass unwraps the protection class's wrapped key using kek and
+// caches the result on the class.
+func (pc *ProtectionClass) UnwrapClass(kek []byte) error {
+	key, err := UnwrapKey(kek, pc.WrappedKey)
+	if err != nil {
+		return err
+	}
+	pc.Key = key
+	return nil
+}
@@ -0,0 +1,13 @@
+package backupcrypto
+
+import (
+	"crypto/sha1"
+	"fmt"
+)
+
+// fileID computes the legacy flat-backup filename iTunes uses to store a
+// domain file's content on disk: the hex SHA1 of "domain-relativePath".
+func fileID(domain, relativePath string) string {
+	sum := sha1.Sum([]byte(domain + "-" + relativePath))
+	return fmt.Sprintf("%x", sum)
+}
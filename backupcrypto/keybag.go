@@ -0,0 +1,103 @@
+// Package backupcrypto implements just enough of Apple's encrypted iTunes
+// backup format to recover the BackupKeybag protection-class keys needed to
+// decrypt a single file (e.g. the Restrictions or Screen Time plist) out of
+// an encrypted backup.
+package backupcrypto
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+)
+
+// Protection classes used by the BackupKeybag. Only the classes pinfinder
+// cares about are named; others are kept around keyed by their raw value.
+const (
+	ClassNone                     = 0
+	ClassCompleteUntilFirstUnlock = 7 // covers most Preferences plists
+)
+
+// ProtectionClass holds the still-wrapped per-class key read out of the
+// keybag, and (once Unwrap has been called) the unwrapped key itself.
+type ProtectionClass struct {
+	Class      int
+	WrappedKey []byte
+	Key        []byte
+}
+
+// Keybag is the parsed representation of the "BackupKeyBag" data blob found
+// in an encrypted backup's Manifest.plist.
+type Keybag struct {
+	Version    uint32
+	Type       uint32
+	UUID       []byte
+	Salt       []byte
+	Iterations int
+	DPSL       []byte // Screen Time / backup-password-specific-layer salt
+	DPIC       int    // iterations for the DPSL derivation
+	Classes    map[int]*ProtectionClass
+}
+
+// ParseKeybag decodes the TLV-encoded BackupKeybag blob: a sequence of
+// 4-byte tag, 4-byte big-endian length, value. A "CLAS" tag starts a new
+// protection class; subsequent WPKY/KTYP/WRAP tags up to the next CLAS (or
+// end of input) belong to that class.
+func ParseKeybag(data []byte) (*Keybag, error) {
+	kb := &Keybag{Classes: make(map[int]*ProtectionClass)}
+	var cur *ProtectionClass
+
+	r := bytes.NewReader(data)
+	for r.Len() > 0 {
+		if r.Len() < 8 {
+			return nil, errors.New("backupcrypto: truncated keybag entry")
+		}
+		var tag [4]byte
+		if _, err := r.Read(tag[:]); err != nil {
+			return nil, err
+		}
+		var length uint32
+		if err := binary.Read(r, binary.BigEndian, &length); err != nil {
+			return nil, err
+		}
+		if int64(length) > int64(r.Len()) {
+			return nil, fmt.Errorf("backupcrypto: %s value length %d exceeds remaining keybag data", tag, length)
+		}
+		value := make([]byte, length)
+		if _, err := io.ReadFull(r, value); err != nil {
+			return nil, fmt.Errorf("backupcrypto: reading %s value: %w", tag, err)
+		}
+
+		switch string(tag[:]) {
+		case "VERS":
+			kb.Version = binary.BigEndian.Uint32(value)
+		case "TYPE":
+			kb.Type = binary.BigEndian.Uint32(value)
+		case "UUID":
+			kb.UUID = value
+		case "SALT":
+			kb.Salt = value
+		case "ITER":
+			kb.Iterations = int(binary.BigEndian.Uint32(value))
+		case "DPSL":
+			kb.DPSL = value
+		case "DPIC":
+			kb.DPIC = int(binary.BigEndian.Uint32(value))
+		case "CLAS":
+			cur = &ProtectionClass{Class: int(binary.BigEndian.Uint32(value))}
+			kb.Classes[cur.Class] = cur
+		case "WPKY", "WRAP":
+			if cur == nil {
+				continue // belongs to the keybag header, not a class; ignore
+			}
+			if string(tag[:]) == "WPKY" {
+				cur.WrappedKey = value
+			}
+		}
+	}
+	if kb.Salt == nil || kb.Iterations == 0 {
+		return nil, errors.New("backupcrypto: keybag missing SALT/ITER header")
+	}
+	return kb, nil
+}
@@ -0,0 +1,67 @@
+package backupcrypto
+
+import (
+	"encoding/base64"
+	"errors"
+	"os"
+	"regexp"
+)
+
+// reManifestEncrypted and reManifestKeybag pull the two fields pinfinder
+// needs out of Manifest.plist without pulling in a full plist library: the
+// file is a small, predictably-formatted Apple XML plist, so a couple of
+// targeted regexps are enough (the same trade-off loadPlist already makes
+// for the restrictions plist).
+var (
+	reManifestEncrypted = regexp.MustCompile(`<key>IsEncrypted</key>\s*<(true|false)\s*/>`)
+	reManifestKeybag    = regexp.MustCompile(`<key>BackupKeyBag</key>\s*<data>\s*([^<]+?)\s*</data>`)
+	reManifestKey       = regexp.MustCompile(`<key>ManifestKey</key>\s*<data>\s*([^<]+?)\s*</data>`)
+)
+
+// ManifestIsEncrypted reports whether the backup at backupDir is password
+// protected, by inspecting its Manifest.plist.
+func ManifestIsEncrypted(backupDir string) (bool, error) {
+	data, err := os.ReadFile(backupDir + "/Manifest.plist")
+	if err != nil {
+		return false, err
+	}
+	m := reManifestEncrypted.FindSubmatch(data)
+	if m == nil {
+		return false, errors.New("backupcrypto: Manifest.plist missing IsEncrypted key")
+	}
+	return string(m[1]) == "true", nil
+}
+
+// ManifestKeybag extracts and decodes the BackupKeyBag blob embedded in
+// Manifest.plist.
+func ManifestKeybag(backupDir string) (*Keybag, error) {
+	data, err := os.ReadFile(backupDir + "/Manifest.plist")
+	if err != nil {
+		return nil, err
+	}
+	m := reManifestKeybag.FindSubmatch(data)
+	if m == nil {
+		return nil, errors.New("backupcrypto: Manifest.plist missing BackupKeyBag")
+	}
+	raw, err := base64.StdEncoding.DecodeString(string(m[1]))
+	if err != nil {
+		return nil, err
+	}
+	return ParseKeybag(raw)
+}
+
+// ManifestKey extracts the raw "ManifestKey" blob from Manifest.plist: a
+// 4-byte (little-endian) protection class followed by the RFC 3394-wrapped
+// key used to decrypt Manifest.db itself, which is AES-CBC encrypted just
+// like any other file in an iOS 10.2+ encrypted backup.
+func ManifestKey(backupDir string) ([]byte, error) {
+	data, err := os.ReadFile(backupDir + "/Manifest.plist")
+	if err != nil {
+		return nil, err
+	}
+	m := reManifestKey.FindSubmatch(data)
+	if m == nil {
+		return nil, errors.New("backupcrypto: Manifest.plist missing ManifestKey")
+	}
+	return base64.StdEncoding.DecodeString(string(m[1]))
+}
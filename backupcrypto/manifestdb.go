@@ -0,0 +1,57 @@
+package backupcrypto
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"regexp"
+
+	_ "github.com/mattn/go-sqlite3"
+)
+
+// reFileKeyEntry locates the NSKeyedArchiver-encoded "EncryptionKey" value
+// inside a Files.file blob from Manifest.db: a protection class byte
+// followed by a 40-byte wrapped AES key, immediately preceded by the
+// archiver's "EncryptionKey" dictionary key string.
+var reFileKeyEntry = regexp.MustCompile(`EncryptionKey.{1,32}?\x04\x10\x00` + `(?s)(.{41})`)
+
+// FileKey is a single file's wrapped per-file key as recorded in
+// Manifest.db, plus the protection class it must be unwrapped with.
+type FileKey struct {
+	Class      int
+	WrappedKey []byte
+}
+
+// LookupFileKey opens the Manifest.db SQLite database at manifestDBPath
+// and returns the wrapped per-file key for the file at relativePath
+// within domain. In an encrypted backup, Manifest.db is itself AES-CBC
+// encrypted, so the caller must pass the path of an already-decrypted
+// copy (see decryptManifestDB) rather than the backup's own Manifest.db.
+func LookupFileKey(manifestDBPath, domain, relativePath string) (*FileKey, error) {
+	db, err := sql.Open("sqlite3", manifestDBPath)
+	if err != nil {
+		return nil, err
+	}
+	defer db.Close()
+
+	var blob []byte
+	row := db.QueryRow(`SELECT file FROM Files WHERE domain = ? AND relativePath = ?`, domain, relativePath)
+	if err := row.Scan(&blob); err != nil {
+		return nil, fmt.Errorf("backupcrypto: looking up %s/%s in Manifest.db: %w", domain, relativePath, err)
+	}
+	return decodeFileKey(blob)
+}
+
+// decodeFileKey pulls the protection class and wrapped key out of a
+// Files.file NSKeyedArchiver blob.
+func decodeFileKey(blob []byte) (*FileKey, error) {
+	m := reFileKeyEntry.FindSubmatch(blob)
+	if m == nil {
+		return nil, errors.New("backupcrypto: no EncryptionKey entry found in file metadata")
+	}
+	entry := m[1]
+	return &FileKey{
+		Class:      int(entry[0]),
+		WrappedKey: append([]byte(nil), entry[1:]...),
+	}, nil
+}
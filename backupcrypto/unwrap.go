@@ -0,0 +1,59 @@
+package backupcrypto
+
+import (
+	"crypto/aes"
+	"encoding/binary"
+	"errors"
+)
+
+// defaultIV is the initial value used by the RFC 3394 AES key wrap/unwrap
+// algorithm.
+var defaultIV = [8]byte{0xA6, 0xA6, 0xA6, 0xA6, 0xA6, 0xA6, 0xA6, 0xA6}
+
+// UnwrapKey reverses RFC 3394 AES key wrapping: it recovers the plaintext
+// key that was wrapped with kek. wrapped must be a multiple of 8 bytes and
+// at least 16 bytes long (one semi-block of IV plus at least one block of
+// key material).
+func UnwrapKey(kek, wrapped []byte) ([]byte, error) {
+	if len(wrapped)%8 != 0 || len(wrapped) < 16 {
+		return nil, errors.New("backupcrypto: wrapped key has invalid length")
+	}
+	block, err := aes.NewCipher(kek)
+	if err != nil {
+		return nil, err
+	}
+
+	n := len(wrapped)/8 - 1
+	r := make([][8]byte, n+1) // r[1..n] hold the key blocks; r[0] is scratch
+	var a [8]byte
+	copy(a[:], wrapped[:8])
+	for i := 1; i <= n; i++ {
+		copy(r[i][:], wrapped[i*8:i*8+8])
+	}
+
+	buf := make([]byte, 16)
+	for j := 5; j >= 0; j-- {
+		for i := n; i >= 1; i-- {
+			t := uint64(n*j) + uint64(i)
+			var tb [8]byte
+			binary.BigEndian.PutUint64(tb[:], t)
+			for k := range a {
+				a[k] ^= tb[k]
+			}
+			copy(buf[:8], a[:])
+			copy(buf[8:], r[i][:])
+			block.Decrypt(buf, buf)
+			copy(a[:], buf[:8])
+			copy(r[i][:], buf[8:])
+		}
+	}
+	if a != defaultIV {
+		return nil, errors.New("backupcrypto: key unwrap integrity check failed")
+	}
+
+	key := make([]byte, 0, n*8)
+	for i := 1; i <= n; i++ {
+		key = append(key, r[i][:]...)
+	}
+	return key, nil
+}
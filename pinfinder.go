@@ -27,40 +27,55 @@
 //
 // This program will examine an iTunes backup folder for an iOS device and attempt
 // to find the PIN used for restricting permissions on the device (NOT the unlock PIN)
+//
+// The core logic lives in package backup so it can be driven from other
+// tools; this file is just the CLI wrapper around it.
 
 package main
 
 import (
 	"bufio"
-	"bytes"
-	"crypto/sha1"
-	"encoding/base64"
-	"encoding/xml"
-	"errors"
+	"context"
+	"encoding/json"
 	"flag"
 	"fmt"
-	"io"
 	"os"
-	"os/user"
 	"path"
-	"path/filepath"
 	"runtime"
 	"strings"
-	"sync"
+	"text/tabwriter"
 	"time"
 
-	"golang.org/x/crypto/pbkdf2"
+	"github.com/fcgll520/pinfinder/backup"
+	"golang.org/x/sync/errgroup"
 )
 
-const (
-	maxPIN  = 10000
-	version = "1.2.0"
-)
+const version = "1.2.0"
 
 var (
-	noPause = flag.Bool("nopause", false, "Set to true to prevent the program pausing for input on completion")
+	noPause  = flag.Bool("nopause", false, "Set to true to prevent the program pausing for input on completion")
+	password = flag.String("password", "", "Backup password, required if the backup is encrypted")
+	mode     = flag.String("mode", "numeric4", "Candidate generation mode: numeric4, numeric6, alphanumeric, or wordlist=<file>")
+	charset  = flag.String("charset", "", "Character set to try, for -mode=alphanumeric")
+	minLen   = flag.Int("minlen", 4, "Minimum candidate length, for -mode=alphanumeric")
+	maxLen   = flag.Int("maxlen", 4, "Maximum candidate length, for -mode=alphanumeric")
+	jsonOut  = flag.Bool("json", false, "Emit a single JSON result on stdout instead of human-readable progress; implies -nopause")
+	all      = flag.Bool("all", false, "Scan every backup in the MobileSync Backup folder instead of just the latest one")
+	workers  = flag.Int("workers", runtime.NumCPU(), "Maximum number of backups to scan concurrently in -all mode")
 )
 
+// jsonResult is the -json flag's stdout payload, meant for scripting from
+// forensics pipelines.
+type jsonResult struct {
+	BackupPath  string `json:"backup_path"`
+	DeviceName  string `json:"device_name,omitempty"`
+	ProductType string `json:"product_type,omitempty"`
+	IOSVersion  string `json:"ios_version,omitempty"`
+	PIN         string `json:"pin"`
+	DurationMS  int64  `json:"duration_ms"`
+	Guesses     int64  `json:"guesses"`
+}
+
 func isDir(p string) bool {
 	s, err := os.Stat(p)
 	if err != nil {
@@ -69,174 +84,6 @@ func isDir(p string) bool {
 	return s.IsDir()
 }
 
-// figure out where iTunes keeps its backups on the current OS
-func findSyncDir() (string, error) {
-	usr, err := user.Current()
-	if err != nil {
-		return "", err
-	}
-	var dir string
-	switch runtime.GOOS {
-	case "darwin":
-		dir = filepath.Join(usr.HomeDir, "Library", "Application Support", "MobileSync", "Backup")
-	case "windows":
-		// vista & newer
-		dir = filepath.Join(usr.HomeDir, "AppData", "Roaming", "Apple Computer", "MobileSync", "Backup")
-		if !isDir(dir) {
-			// XP; untested.
-			dir = filepath.Join("Documents and Settings", usr.Username, "Application Data", "Apple Computer", "MobileSync", "Backup")
-		}
-	default:
-		return "", errors.New("Could not detect backup directory for this operating system; pass explicitly")
-	}
-	if !isDir(dir) {
-		return "", fmt.Errorf("Directory %s does not exist", dir)
-	}
-	return dir, nil
-}
-
-// Fidn the latest backup folder
-func findLatestBackup(backupDir string) (string, error) {
-	d, err := os.Open(backupDir)
-	if err != nil {
-		return "", err
-	}
-	files, err := d.Readdir(10000)
-	if err != nil {
-		return "", err
-	}
-	var newest string
-	var lastMT time.Time
-
-	for _, fi := range files {
-		if mt := fi.ModTime(); mt.After(lastMT) {
-			lastMT = mt
-			newest = fi.Name()
-		}
-	}
-	if newest != "" {
-		return filepath.Join(backupDir, newest), nil
-	}
-	return "", errors.New("No backup directories found in " + backupDir)
-}
-
-type plist struct {
-	Path string
-	Keys []string `xml:"dict>key"`
-	Data []string `xml:"dict>data"`
-}
-
-func (p *plist) DumpTo(w io.Writer) error {
-	f, err := os.Open(p.Path)
-	if err != nil {
-		return fmt.Errorf("Failed to dump plist data: %s", err)
-	}
-	defer f.Close()
-	io.Copy(w, f)
-	return nil
-}
-
-func loadPlist(fn string) (*plist, error) {
-	var p plist
-	f, err := os.Open(fn)
-	if err != nil {
-		return nil, err
-	}
-	defer f.Close()
-	if err := xml.NewDecoder(f).Decode(&p); err != nil {
-		return nil, err
-	}
-	p.Path = fn
-	return &p, nil
-}
-
-func findRestrictions(fpath string) (*plist, error) {
-	d, err := os.Open(fpath)
-	if err != nil {
-		return nil, err
-	}
-	defer d.Close()
-	fl, err := d.Readdir(-1)
-	if err != nil {
-		return nil, err
-	}
-	c := 0
-	for _, fi := range fl {
-		if !fi.Mode().IsRegular() {
-			continue
-		}
-		if size := fi.Size(); size < 300 || size > 500 {
-			continue
-		}
-		if pl, err := loadPlist(path.Join(fpath, fi.Name())); err == nil {
-			c++
-			if len(pl.Keys) == 2 && len(pl.Data) == 2 && pl.Keys[0] == "RestrictionsPasswordKey" {
-				return pl, nil
-			}
-		}
-	}
-	if c == 0 {
-		return nil, errors.New("No plist files; are you sure you have the right directory?")
-	}
-	return nil, errors.New("No matching plist file - Are parental restrictions turned on?")
-}
-
-func parseRestrictions(pl *plist) (pw, salt []byte) {
-	pw, _ = base64.StdEncoding.DecodeString(strings.TrimSpace(pl.Data[0]))
-	salt, _ = base64.StdEncoding.DecodeString(strings.TrimSpace(pl.Data[1]))
-	return pw, salt
-}
-
-type swg struct{ sync.WaitGroup }
-
-func (wg *swg) WaitChan() chan struct{} {
-	c := make(chan struct{}, 1)
-	go func() {
-		wg.Wait()
-		c <- struct{}{}
-	}()
-	return c
-}
-
-// use all available cores to brute force the PIN
-func findPIN(key, salt []byte) (string, error) {
-	found := make(chan string)
-	var wg swg
-	var start, end int
-
-	perCPU := maxPIN / runtime.NumCPU()
-
-	for i := 0; i < runtime.NumCPU(); i++ {
-		wg.Add(1)
-		if i == runtime.NumCPU()-1 {
-			end = maxPIN
-		} else {
-			end += perCPU
-		}
-
-		go func(start, end int) {
-			for j := start; j < end; j++ {
-				guess := fmt.Sprintf("%04d", j)
-				k := pbkdf2.Key([]byte(guess), salt, 1000, len(key), sha1.New)
-				if bytes.Equal(k, key) {
-					found <- guess
-					return
-				}
-			}
-			wg.Done()
-		}(start, end)
-
-		start += perCPU
-	}
-
-	select {
-	case <-wg.WaitChan():
-		return "", errors.New("failed to calculate PIN number")
-	case pin := <-found:
-		return pin, nil
-	}
-}
-
 func exit(status int, addUsage bool, errfmt string, a ...interface{}) {
 	if errfmt != "" {
 		fmt.Fprintf(os.Stderr, errfmt+"\n", a...)
@@ -244,7 +91,7 @@ func exit(status int, addUsage bool, errfmt string, a ...interface{}) {
 	if addUsage {
 		usage()
 	}
-	if !*noPause {
+	if !*noPause && !*jsonOut {
 		fmt.Printf("Press Enter to exit")
 		bufio.NewReader(os.Stdin).ReadBytes('\n')
 	}
@@ -264,19 +111,39 @@ func main() {
 	var backupDir, syncDir string
 	var err error
 
-	fmt.Println("PIN Finder", version)
-
 	flag.Parse()
 
+	if !*jsonOut {
+		fmt.Println("PIN Finder", version)
+	}
+
 	args := flag.Args()
+
+	if *all {
+		switch len(args) {
+		case 0:
+			sd, err := backup.FindSyncDir()
+			if err != nil {
+				exit(101, true, err.Error())
+			}
+			syncDir = sd
+		case 1:
+			syncDir = args[0]
+		default:
+			exit(102, true, "Too many arguments")
+		}
+		runAll(syncDir)
+		return
+	}
+
 	switch len(args) {
 	case 0:
-		syncDir, err = findSyncDir()
+		syncDir, err = backup.FindSyncDir()
 		if err != nil {
-			fmt.Println(err.Error)
+			fmt.Println(err.Error())
 			usage()
 		}
-		backupDir, err = findLatestBackup(syncDir)
+		backupDir, err = backup.FindLatestBackup(syncDir)
 		if err != nil {
 			exit(101, true, err.Error())
 		}
@@ -292,24 +159,158 @@ func main() {
 		exit(103, true, "Directory not found: %s", backupDir)
 	}
 
-	fmt.Println("Searching backup at", backupDir)
-	pl, err := findRestrictions(backupDir)
+	if !*jsonOut {
+		fmt.Println("Searching backup at", backupDir)
+	}
+
+	var pl *backup.Plist
+	var cracker backup.Cracker
+	if encrypted, encErr := backup.IsEncrypted(backupDir); encErr == nil && encrypted {
+		pw := *password
+		if pw == "" {
+			if *jsonOut {
+				exit(106, false, "Backup is encrypted; pass -password")
+			}
+			fmt.Print("Backup is encrypted; enter backup password: ")
+			line, err := bufio.NewReader(os.Stdin).ReadString('\n')
+			if err != nil {
+				exit(106, false, "Failed to read backup password: %s", err)
+			}
+			pw = strings.TrimSpace(line)
+		}
+		pl, cracker, err = backup.FindEncryptedRestrictions(backupDir, pw)
+		if err != nil {
+			exit(104, false, "Failed to decrypt restrictions plist file: %s", err.Error())
+		}
+	} else {
+		pl, cracker, err = backup.FindRestrictions(backupDir)
+		if err != nil {
+			exit(104, false, "Failed to find/load restrictions plist file: %s", err.Error())
+		}
+	}
+
+	key, salt := backup.ParseRestrictions(pl)
+
+	source, err := backup.ParseMode(*mode, *charset, *minLen, *maxLen)
 	if err != nil {
-		exit(104, false, "Failed to find/load restrictions plist file: ", err.Error())
+		exit(107, true, "Invalid -mode: %s", err)
 	}
 
-	key, salt := parseRestrictions(pl)
+	var progress func(backup.Stats)
+	if !*jsonOut {
+		fmt.Print("Finding PIN...")
+		progress = func(s backup.Stats) {
+			rate := float64(s.Guesses) / s.Elapsed.Seconds()
+			if s.Total < 0 {
+				fmt.Fprintf(os.Stderr, "\n%d guesses (%.0f/sec)", s.Guesses, rate)
+				return
+			}
+			remaining := s.Total - s.Guesses
+			eta := time.Duration(float64(remaining) / rate * float64(time.Second))
+			fmt.Fprintf(os.Stderr, "\n%d/%d guesses (%.0f/sec, ETA %s)", s.Guesses, s.Total, rate, eta)
+		}
+	}
 
-	fmt.Print("Finding PIN...")
 	startTime := time.Now()
-	pin, err := findPIN(key, salt)
+	pin, stats, err := backup.FindPIN(context.Background(), key, salt, backup.FindPINOptions{
+		Cracker:  cracker,
+		Source:   source,
+		Progress: progress,
+	})
 	if err != nil {
-		// Failed to break the PIN; dump the plist data for debugging purposes
-		fmt.Fprintln(os.Stderr, err.Error()+"\n")
-		fmt.Fprintln(os.Stderr, "Source data file: ", pl.Path)
-		pl.DumpTo(os.Stderr)
+		if !*jsonOut {
+			// Failed to break the PIN; dump the plist data for debugging purposes
+			fmt.Fprintln(os.Stderr, err.Error()+"\n")
+			fmt.Fprintln(os.Stderr, "Source data file: ", pl.Path)
+			pl.DumpTo(os.Stderr)
+		}
 		exit(105, false, "")
 	}
+
+	if *jsonOut {
+		result := jsonResult{
+			BackupPath: backupDir,
+			PIN:        pin,
+			DurationMS: time.Since(startTime).Milliseconds(),
+			Guesses:    stats.Guesses,
+		}
+		if info, err := backup.LoadDeviceInfo(backupDir); err == nil {
+			result.DeviceName = info.DeviceName
+			result.ProductType = info.ProductType
+			result.IOSVersion = info.IOSVersion
+		}
+		json.NewEncoder(os.Stdout).Encode(result)
+		exit(0, false, "")
+	}
+
 	fmt.Printf(" FOUND!\nPIN number is: %s (found in %s)\n", pin, time.Since(startTime))
 	exit(0, false, "")
 }
+
+// runAll implements -all: enumerate every backup in syncDir, then classify
+// and crack each one concurrently, bounded by -workers.
+func runAll(syncDir string) {
+	dirs, err := backup.EnumerateBackups(syncDir)
+	if err != nil {
+		exit(101, true, err.Error())
+	}
+
+	source, err := backup.ParseMode(*mode, *charset, *minLen, *maxLen)
+	if err != nil {
+		exit(107, true, "Invalid -mode: %s", err)
+	}
+
+	if *workers < 1 {
+		exit(107, true, "-workers must be at least 1")
+	}
+
+	g, ctx := errgroup.WithContext(context.Background())
+	g.SetLimit(*workers)
+
+	summaries := make([]backup.BackupSummary, len(dirs))
+	for i, dir := range dirs {
+		i, dir := i, dir
+		g.Go(func() error {
+			summaries[i] = backup.ScanBackup(ctx, dir, backup.ScanOptions{
+				Password: *password,
+				Source:   source,
+			})
+			return nil
+		})
+	}
+	g.Wait()
+
+	if *jsonOut {
+		json.NewEncoder(os.Stdout).Encode(summaries)
+	} else {
+		printSummaries(summaries)
+	}
+	exit(0, false, "")
+}
+
+// printSummaries renders the -all scan results as a human-readable table.
+func printSummaries(summaries []backup.BackupSummary) {
+	w := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(w, "DEVICE\tIOS VERSION\tLAST BACKUP\tBACKUP PATH\tPIN")
+	for _, s := range summaries {
+		name, ver := "(unknown)", "(unknown)"
+		if s.Info != nil {
+			if s.Info.DeviceName != "" {
+				name = s.Info.DeviceName
+			}
+			if s.Info.IOSVersion != "" {
+				ver = s.Info.IOSVersion
+			}
+		}
+		date := "(unknown)"
+		if !s.BackupDate.IsZero() {
+			date = s.BackupDate.Local().Format("2006-01-02 15:04")
+		}
+		pin := s.PIN
+		if pin == "" {
+			pin = s.Status
+		}
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\n", name, ver, date, s.Path, pin)
+	}
+	w.Flush()
+}